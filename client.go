@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	keepAlive    = &atomic.Bool{} // keepalive=on|off, default on
+	http2Enabled = &atomic.Bool{} // http2=on|off, default on
+
+	clientMu sync.RWMutex
+	client   *http.Client
+
+	proxyMu sync.Mutex
+
+	dnsCacheMu sync.Mutex
+	dnsCache   = map[string][]string{}
+)
+
+func init() {
+	keepAlive.Store(true)
+	http2Enabled.Store(true)
+}
+
+func setProxy(v string) {
+	proxyMu.Lock()
+	proxyURL = v
+	proxyMu.Unlock()
+}
+
+func getProxy() string {
+	proxyMu.Lock()
+	defer proxyMu.Unlock()
+	return proxyURL
+}
+
+// currentClient returns the shared *http.Client, rebuilt whenever the pool
+// sizing or transport settings change; see rebuildClient.
+func currentClient() *http.Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return client
+}
+
+// rebuildClient recreates the shared client from the current settings. It
+// runs at startup and again whenever p=, keepalive=, http2= or proxy= change
+// live, so a tune takes effect on the next request without a restart.
+func rebuildClient() {
+	p := int(parallelism.Load())
+	if p < 1 {
+		p = 1
+	}
+
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: p,
+		MaxConnsPerHost:     p,
+		IdleConnTimeout:     90 * time.Second,
+		DisableCompression:  false,
+		ForceAttemptHTTP2:   http2Enabled.Load(),
+		DisableKeepAlives:   !keepAlive.Load(),
+		DialContext:         cachedDialContext,
+	}
+
+	if raw := getProxy(); raw != "" {
+		if u, err := url.Parse(raw); err == nil {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+
+	if insecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	clientMu.Lock()
+	old := client
+	client = &http.Client{Transport: transport}
+	clientMu.Unlock()
+
+	if old != nil {
+		old.CloseIdleConnections() // drop the replaced transport's pooled conns instead of leaking them until GC
+	}
+}
+
+// cachedDialContext resolves and caches a host's addresses so repeat
+// requests to the same host skip redundant DNS lookups.
+func cachedDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	dnsCacheMu.Lock()
+	ips, ok := dnsCache[host]
+	dnsCacheMu.Unlock()
+
+	if !ok {
+		ips, err = net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		dnsCacheMu.Lock()
+		dnsCache[host] = ips
+		dnsCacheMu.Unlock()
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// boolOnOffSetting adapts an *atomic.Bool to the `storage` interface so it
+// can be driven by "name=on"/"name=off" live commands.
+type boolOnOffSetting struct {
+	*atomic.Bool
+}
+
+func (b boolOnOffSetting) Store(val string) error {
+	switch val {
+	case "on":
+		b.Bool.Store(true)
+	case "off":
+		b.Bool.Store(false)
+	default:
+		return fmt.Errorf("expected on or off, got %q", val)
+	}
+	return nil
+}
+
+// proxySetting adapts the live proxy URL to the `storage` interface.
+type proxySetting struct{}
+
+func (proxySetting) Store(val string) error {
+	setProxy(val)
+	return nil
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkpointFile is where the highest fully-completed contiguous line number
+// is persisted, so a re-run of `hurl <file>` can auto-resume. It's keyed off
+// the input file's path so running a different file (or the same file from
+// two directories) never picks up someone else's leftover checkpoint; set by
+// resolveSkip once the input path is known.
+var checkpointFile string
+
+// checkpointPathFor derives the checkpoint file name for a given input path.
+func checkpointPathFor(inputPath string) string {
+	abs, err := filepath.Abs(inputPath)
+	if err != nil {
+		abs = inputPath
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return fmt.Sprintf(".hurl-state.%x", sum[:8])
+}
+
+// workItem is one queued unit of work: a resolved request tagged with its
+// source line number (seq) and how many times it's been attempted so far.
+type workItem struct {
+	seq     int64
+	req     *Request
+	attempt int
+}
+
+// retryItem is a failed item waiting out its backoff before being redispatched.
+type retryItem struct {
+	item    workItem
+	readyAt time.Time
+}
+
+var (
+	shardsMu sync.Mutex
+	// shards[i] is the channel owned by worker i; routing an item's seq
+	// through seq % parallelism onto this slice keeps retries on the same
+	// worker (and thus the same pooled connections).
+	shards []chan workItem
+
+	spawnedMu sync.Mutex
+	// spawned tracks which worker indices have a goroutine running, so
+	// raising parallelism back up after a drop never starts a second
+	// goroutine reading the same shard; see spawnWorker.
+	spawned = map[int64]bool{}
+
+	// itemsWG tracks items dispatched but not yet in a terminal state
+	// (written out or permanently failed), so a clean EOF can wait for
+	// in-flight retries to finish before tearing workers down.
+	itemsWG sync.WaitGroup
+
+	completedMu sync.Mutex
+	completed         = map[int64]bool{} // seq numbers done, pending contiguous compaction
+	checkpoint  int64 = -1               // highest seq such that every seq in [0, checkpoint] is done
+)
+
+// ensureShard returns the channel for shard idx, creating it (and any gaps
+// before it) on first use.
+func ensureShard(idx int64) chan workItem {
+	shardsMu.Lock()
+	defer shardsMu.Unlock()
+	for int64(len(shards)) <= idx {
+		shards = append(shards, make(chan workItem, 16))
+	}
+	return shards[idx]
+}
+
+// shardForSeq picks the shard that owns seq under the current parallelism.
+func shardForSeq(seq int64) chan workItem {
+	n := parallelism.Load()
+	if n < 1 {
+		n = 1
+	}
+	return ensureShard(seq % n)
+}
+
+// spawnWorker starts worker idx, registering its shard before the goroutine
+// runs so dispatch can never race ahead of shard creation. It's idempotent:
+// calling it again for an idx that's already running (e.g. parallelism was
+// raised back up after a drop) is a no-op, since that worker's goroutine is
+// still alive and parked, not exited; see startWorker.
+func spawnWorker(ctx context.Context, idx int64) {
+	spawnedMu.Lock()
+	if spawned[idx] {
+		spawnedMu.Unlock()
+		return
+	}
+	spawned[idx] = true
+	spawnedMu.Unlock()
+
+	ch := ensureShard(idx)
+	wg.Add(1)
+	go startWorker(ctx, idx, ch)
+}
+
+// startWorker processes items from ch for as long as idx is within the
+// current parallelism. If parallelism drops below idx, the worker doesn't
+// exit (that would abandon ch - nothing else ever reads a retired shard,
+// since shards are never closed) - instead it parks and forwards anything
+// it receives to whatever shard is live now, via shardForSeq. If
+// parallelism is later raised again, the same goroutine resumes processing.
+func startWorker(ctx context.Context, idx int64, ch chan workItem) {
+	defer wg.Done()
+	active := parallelism.Load() > idx
+	if active {
+		workers.Add(1)
+	}
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	workRate := rate.Load()
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			workRate = rate.Load()
+			if nowActive := parallelism.Load() > idx; nowActive != active {
+				if nowActive {
+					workers.Add(1)
+				} else {
+					workers.Add(-1)
+				}
+				active = nowActive
+			}
+		case item, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !active {
+				select {
+				case shardForSeq(item.seq) <- item:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			// enforce max speed
+			if time.Since(last) < time.Second/time.Duration(workRate) {
+				time.Sleep(time.Second/time.Duration(workRate) - time.Since(last))
+			}
+			last = time.Now()
+			handleItem(ctx, item)
+		}
+	}
+}
+
+// handleItem runs one attempt of item. On success, or once retries are
+// exhausted, it reports the result and marks the seq complete. Otherwise it
+// schedules a backed-off retry on the same shard.
+func handleItem(ctx context.Context, item workItem) {
+	res := attemptReq(ctx, item.req)
+	res.Seq = item.seq
+	res.Attempts = item.attempt
+	if res.Error == "" || item.attempt >= retries {
+		writeResult(res)
+		pushMonitorSample(res)
+		markCompleted(item.seq)
+		itemsWG.Done()
+		return
+	}
+	scheduleRetry(ctx, retryItem{
+		item:    workItem{seq: item.seq, req: item.req, attempt: item.attempt + 1},
+		readyAt: time.Now().Add(backoff(item.attempt)),
+	})
+}
+
+// backoff returns the exponential, jittered delay before retrying an item
+// that just failed on the given attempt number. base is clamped to be
+// positive so a misconfigured (zero or negative) perTryTimeout can't make
+// rand.Int63n panic.
+func backoff(attempt int) time.Duration {
+	base := perTryTimeout
+	if base <= 0 {
+		base = time.Second
+	}
+	d := base << uint(attempt-1)
+	return d + time.Duration(rand.Int63n(int64(base)))
+}
+
+// scheduleRetry waits out ri's own backoff on its own timer and then
+// redispatches it to its shard. Each retry gets an independent goroutine and
+// timer so a long backoff on one item can never head-of-line-block another
+// item whose backoff has already elapsed.
+func scheduleRetry(ctx context.Context, ri retryItem) {
+	go func() {
+		if wait := time.Until(ri.readyAt); wait > 0 {
+			t := time.NewTimer(wait)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case shardForSeq(ri.item.seq) <- ri.item:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// attemptReq performs a single HTTP try, capturing metrics along the way.
+// Retries now live at the dispatch layer (see handleItem), not here.
+func attemptReq(ctx context.Context, r *Request) (res result) {
+	res.URL = r.URL
+	res.Method = r.Method
+	start := time.Now()
+	rctx, rcancel := context.WithTimeout(ctx, perTryTimeout)
+	defer rcancel()
+	trace := &timing{start: time.Now()}
+	httpReq, err := http.NewRequestWithContext(httptrace.WithClientTrace(rctx, trace.trace()), r.Method, r.URL, bodyReader(r.Body))
+	if err != nil {
+		res.Error = err.Error()
+		res.ErrorClass = errorClass(err)
+		return res
+	}
+	httpReq.Header = r.Header.Clone()
+	resp, err := currentClient().Do(httpReq)
+	if err != nil {
+		res.latency = time.Since(start)
+		res.LatencyMS = ms(res.latency)
+		res.Error = err.Error()
+		res.ErrorClass = errorClass(err)
+		return res
+	}
+	n := copyResponseBody(r.URL, resp.Body)
+	resp.Body.Close()
+	res.Status = resp.StatusCode
+	res.Bytes = n
+	res.DNSMS = ms(since(trace.dnsDone, trace.dnsStart))
+	res.ConnectMS = ms(since(trace.connectDone, trace.connectStart))
+	res.TLSMS = ms(since(trace.tlsDone, trace.tlsStart))
+	res.TTFBMS = ms(since(trace.firstByte, trace.start))
+	res.latency = time.Since(start)
+	res.LatencyMS = ms(res.latency)
+	if r.ExpectedStatus != 0 && resp.StatusCode != r.ExpectedStatus {
+		res.Error = fmt.Sprintf("expected status %d, got %d", r.ExpectedStatus, resp.StatusCode)
+		res.ErrorClass = "status_mismatch"
+	}
+	return res
+}
+
+// bodyReader wraps a request body for http.NewRequestWithContext, or returns
+// nil for an empty body so GET-style requests don't get a Content-Length: 0.
+func bodyReader(body []byte) io.Reader {
+	if len(body) == 0 {
+		return nil
+	}
+	return bytes.NewReader(body)
+}
+
+// markCompleted records seq as done and advances checkpoint over any now-
+// contiguous run, so flushCheckpoint only ever persists a safe resume point.
+func markCompleted(seq int64) {
+	completedMu.Lock()
+	defer completedMu.Unlock()
+	completed[seq] = true
+	for completed[checkpoint+1] {
+		checkpoint++
+		delete(completed, checkpoint)
+	}
+}
+
+// resolveSkip keys checkpointFile off inputPath, then applies it as the
+// default skip count when the user didn't pass -skip explicitly, and seeds
+// checkpoint accordingly.
+func resolveSkip(inputPath string) {
+	checkpointFile = checkpointPathFor(inputPath)
+	if !skipExplicit {
+		if cp, err := readCheckpoint(checkpointFile); err == nil {
+			skip = int(cp) + 1
+		}
+	}
+	checkpoint = int64(skip) - 1
+}
+
+func readCheckpoint(path string) (int64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// flushCheckpoint atomically persists the current checkpoint to disk.
+func flushCheckpoint() {
+	completedMu.Lock()
+	cp := checkpoint
+	completedMu.Unlock()
+	if cp < 0 {
+		return
+	}
+	tmp := checkpointFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(cp, 10)), 0o644); err != nil {
+		log.Println("checkpoint:", err)
+		return
+	}
+	if err := os.Rename(tmp, checkpointFile); err != nil {
+		log.Println("checkpoint:", err)
+	}
+}
+
+// clearCheckpoint removes the checkpoint file after a run finishes normally
+// (no cancellation), so a deliberate fresh re-run of the same input doesn't
+// silently resume from a prior completed pass.
+func clearCheckpoint() {
+	if err := os.Remove(checkpointFile); err != nil && !os.IsNotExist(err) {
+		log.Println("checkpoint:", err)
+	}
+}
+
+// runCheckpointer flushes the checkpoint periodically and once more on shutdown.
+func runCheckpointer(ctx context.Context) {
+	t := time.NewTicker(2 * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCheckpoint()
+			return
+		case <-t.C:
+			flushCheckpoint()
+		}
+	}
+}
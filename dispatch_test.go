@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffClampsNonPositiveBase(t *testing.T) {
+	orig := perTryTimeout
+	defer func() { perTryTimeout = orig }()
+
+	for _, base := range []time.Duration{0, -time.Second} {
+		perTryTimeout = base
+		// backoff used to panic via rand.Int63n(0) whenever perTryTimeout
+		// was non-positive (e.g. after a live "t=0s" command); it must
+		// instead fall back to a sane default.
+		d := backoff(1)
+		if d <= 0 {
+			t.Errorf("backoff(1) with perTryTimeout=%v = %v, want > 0", base, d)
+		}
+	}
+}
+
+func TestBackoffGrowsWithAttempt(t *testing.T) {
+	orig := perTryTimeout
+	defer func() { perTryTimeout = orig }()
+	perTryTimeout = 10 * time.Millisecond
+
+	// backoff is jittered, but its floor (ignoring jitter) must still
+	// double with each attempt.
+	if d := backoff(1); d < perTryTimeout {
+		t.Errorf("backoff(1) = %v, want >= %v", d, perTryTimeout)
+	}
+	if d := backoff(3); d < 4*perTryTimeout {
+		t.Errorf("backoff(3) = %v, want >= %v", d, 4*perTryTimeout)
+	}
+}
+
+func TestCheckpointPathForIsStableAndScoped(t *testing.T) {
+	a := checkpointPathFor("a.txt")
+	if got := checkpointPathFor("a.txt"); got != a {
+		t.Errorf("checkpointPathFor is not stable: %q != %q", got, a)
+	}
+	if b := checkpointPathFor("b.txt"); b == a {
+		t.Errorf("checkpointPathFor(%q) and checkpointPathFor(%q) collided: %q", "a.txt", "b.txt", a)
+	}
+}
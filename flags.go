@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// headerFlag collects repeated -header Name:Value flags into the default
+// header set applied to every request; see parseLine.
+type headerFlag []string
+
+func (h *headerFlag) String() string { return strings.Join(*h, ",") }
+func (h *headerFlag) Set(v string) error {
+	*h = append(*h, v)
+	return nil
+}
+
+var (
+	flagParallelism    = flag.Int64("p", 1, "number of parallel workers")
+	flagRate           = flag.Int64("r", 1, "max requests/sec per worker")
+	flagTimeout        = flag.Duration("t", 10*time.Second, "per-try timeout")
+	flagRetries        = flag.Int("c", 3, "retries per request")
+	flagReportInterval = flag.Int64("s", 5, "monitor report interval in seconds")
+	flagSkip           = flag.Int("skip", 0, "number of input lines to skip")
+	flagOutput         = flag.String("o", "text", "output format: text|json|ndjson|csv")
+	flagMethod         = flag.String("method", "GET", "default HTTP method")
+	flagHeader         headerFlag
+	flagBodyFile       = flag.String("body-file", "", "file whose contents become the default request body")
+	flagInsecure       = flag.Bool("insecure", false, "skip TLS certificate verification")
+	flagProxy          = flag.String("proxy", "", "proxy URL for outgoing requests")
+	flagConfigPath     = flag.String("config", "", "JSON config file seeding the same settings")
+	flagTemplate       = flag.String("template", "", "template file with ${var} placeholders, rendered per row of the TSV/CSV input")
+	flagOutDir         = flag.String("out-dir", "", "directory to save response bodies to, named by SHA256(url)")
+)
+
+func init() {
+	flag.Var(&flagHeader, "header", "default header as Name:Value (repeatable)")
+}
+
+// Package-level settings seeded by parseFlags; attemptReq and friends read these.
+var (
+	defaultMethod      = "GET"
+	defaultHeaderLines []string
+	defaultBodyFile    string
+	insecureSkipVerify bool
+	proxyURL           string
+	templatePath       string
+	outDir             string
+
+	// skipExplicit is true when the user passed -skip themselves, in which
+	// case it overrides the .hurl-state checkpoint (see resolveSkip).
+	skipExplicit bool
+)
+
+// fileConfig mirrors the CLI flags so a JSON file can seed the same settings;
+// unset fields leave the corresponding flag (or its default) in place.
+type fileConfig struct {
+	Parallelism    *int64   `json:"parallelism"`
+	Rate           *int64   `json:"rate"`
+	Timeout        *string  `json:"timeout"`
+	Retries        *int     `json:"retries"`
+	ReportInterval *int64   `json:"report_interval"`
+	Skip           *int     `json:"skip"`
+	Output         *string  `json:"output"`
+	Method         *string  `json:"method"`
+	Headers        []string `json:"headers"`
+	BodyFile       *string  `json:"body_file"`
+	Insecure       *bool    `json:"insecure"`
+	Proxy          *string  `json:"proxy"`
+	Template       *string  `json:"template"`
+	OutDir         *string  `json:"out_dir"`
+}
+
+func loadFileConfig(path string) (*fileConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg fileConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// parseFlags parses the CLI flags and an optional -config file into the
+// package's settings. Explicitly-passed flags win over the config file,
+// which in turn wins over flag defaults. It returns the remaining
+// positional args (just the input file).
+func parseFlags(args []string) []string {
+	if err := flag.CommandLine.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+
+	var cfg *fileConfig
+	if *flagConfigPath != "" {
+		var err error
+		cfg, err = loadFileConfig(*flagConfigPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	p := *flagParallelism
+	if cfg != nil && cfg.Parallelism != nil && !explicit["p"] {
+		p = *cfg.Parallelism
+	}
+	parallelism.Store(p)
+
+	r := *flagRate
+	if cfg != nil && cfg.Rate != nil && !explicit["r"] {
+		r = *cfg.Rate
+	}
+	rate.Store(r)
+
+	timeout := *flagTimeout
+	if cfg != nil && cfg.Timeout != nil && !explicit["t"] {
+		d, err := time.ParseDuration(*cfg.Timeout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		timeout = d
+	}
+	if timeout <= 0 {
+		log.Fatalf("timeout must be > 0, got %s", timeout)
+	}
+	perTryTimeout = timeout
+
+	c := *flagRetries
+	if cfg != nil && cfg.Retries != nil && !explicit["c"] {
+		c = *cfg.Retries
+	}
+	retries = c
+
+	interval := *flagReportInterval
+	if cfg != nil && cfg.ReportInterval != nil && !explicit["s"] {
+		interval = *cfg.ReportInterval
+	}
+	reportInterval.Store(interval)
+
+	sk := *flagSkip
+	if cfg != nil && cfg.Skip != nil && !explicit["skip"] {
+		sk = *cfg.Skip
+	}
+	skip = sk
+	skipExplicit = explicit["skip"]
+
+	out := *flagOutput
+	if cfg != nil && cfg.Output != nil && !explicit["o"] {
+		out = *cfg.Output
+	}
+	outputFormat = out
+
+	method := *flagMethod
+	if cfg != nil && cfg.Method != nil && !explicit["method"] {
+		method = *cfg.Method
+	}
+	defaultMethod = method
+
+	headers := []string(flagHeader)
+	if cfg != nil && len(cfg.Headers) > 0 && len(headers) == 0 {
+		headers = cfg.Headers
+	}
+	defaultHeaderLines = headers
+
+	bodyFile := *flagBodyFile
+	if cfg != nil && cfg.BodyFile != nil && !explicit["body-file"] {
+		bodyFile = *cfg.BodyFile
+	}
+	defaultBodyFile = bodyFile
+
+	insecure := *flagInsecure
+	if cfg != nil && cfg.Insecure != nil && !explicit["insecure"] {
+		insecure = *cfg.Insecure
+	}
+	insecureSkipVerify = insecure
+
+	proxy := *flagProxy
+	if cfg != nil && cfg.Proxy != nil && !explicit["proxy"] {
+		proxy = *cfg.Proxy
+	}
+	proxyURL = proxy
+
+	tmpl := *flagTemplate
+	if cfg != nil && cfg.Template != nil && !explicit["template"] {
+		tmpl = *cfg.Template
+	}
+	templatePath = tmpl
+
+	dir := *flagOutDir
+	if cfg != nil && cfg.OutDir != nil && !explicit["out-dir"] {
+		dir = *cfg.OutDir
+	}
+	outDir = dir
+
+	return flag.Args()
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -14,14 +13,11 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 var (
 	parallelism   = &atomic.Int64{} // parallelism
 	rate          = &atomic.Int64{} // max-rate per worker
-	input         = make(chan string)
 	workers       = &atomic.Int64{}
 	wg            = sync.WaitGroup{}
 	perTryTimeout = 10 * time.Second
@@ -32,15 +28,35 @@ var (
 func main() {
 	log.SetFlags(0)
 	log.SetOutput(os.Stderr)
-	parallelism.Store(1)
-	rate.Store(1)
+	args := parseFlags(os.Args[1:])
 	workers.Store(0)
-	wg.Add(1)
-	go startWorker() // single worker to start
+	rebuildClient()
+	initDefaultHeaders()
+	loadDefaultBody()
 
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	// read urls from input file
+	if len(args) < 1 {
+		log.Fatal("missing input file; usage: hurl [flags] <url-list-file>")
+	}
+	readFile, err := os.Open(args[0])
+	if err != nil {
+		log.Println(err)
+	}
+	defer readFile.Close()
+
+	resolveSkip(args[0])
+	// one worker per shard for the starting parallelism (at least one)
+	spawnWorker(ctx, 0)
+	for i := int64(1); i < parallelism.Load(); i++ {
+		spawnWorker(ctx, i)
+	}
+
+	go activeMonitor.run(ctx)
+	go runCheckpointer(ctx)
+
 	commands := make(chan string, 1)
 
 	// routine to change settings live
@@ -55,112 +71,121 @@ func main() {
 		log.Println("stopped reading input", inScanner.Err())
 	}()
 
-	// read urls from input file
-	if len(os.Args) < 2 {
-		log.Fatal("missing input file; usage: hurl <url-list-file>")
-	}
-	readFile, err := os.Open(os.Args[1])
-	if err != nil {
-		log.Println(err)
+	var template string
+	var columns []string
+	if templatePath != "" {
+		b, err := os.ReadFile(templatePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		template = strings.TrimSpace(string(b))
 	}
-	defer readFile.Close()
 
-	if len(os.Args) > 2 {
-		skip, _ = strconv.Atoi(os.Args[2])
-	}
 	fileScanner := bufio.NewScanner(readFile)
 	fileScanner.Split(bufio.ScanLines)
+	if template != "" && fileScanner.Scan() {
+		columns = splitRow(strings.TrimSpace(fileScanner.Text()))
+	}
+	finished := false
 	linesProcessed := 0
 	for fileScanner.Scan() {
 		text := strings.TrimSpace(fileScanner.Text())
-		line := linesProcessed
+		seq := int64(linesProcessed)
 		linesProcessed++
 		if skip > 0 {
 			skip--
 			continue
 		}
-		if text != "" {
+		if text == "" {
+			continue
+		}
+		var req *Request
+		if template != "" {
+			req = parseLine(template, rowVars(columns, splitRow(text)))
+		} else {
+			req = parseLine(text, nil)
+		}
+		item := workItem{seq: seq, req: req, attempt: 1}
+		itemsWG.Add(1)
+	sendLoop:
+		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("stopping at line %d (start with 'hurl <file> <skip>' to resume): %s", line, ctx.Err())
+				log.Printf("stopping at line %d: %s (checkpoint saved, resume is automatic)", seq, ctx.Err())
 				goto exit
 			case command := <-commands:
-				if true &&
-					// usage, to control parallelism, write: p=100
-					!setting("p", "parallelism", command, atomicIntSetting{parallelism}) &&
-					// usage, to control the max-rate per worker, set to 16/s, write: r=16
-					!setting("r", "rate", command, atomicIntSetting{rate}) &&
-					// usage, to control the per try timeout, write: t=10s
-					!setting("t", "timeout", command, durationSetting{&perTryTimeout}) &&
-					// usage, to control the retries, write: c=3
-					!setting("c", "retries", command, intSetting{&retries}) {
-					log.Println("unknown command", command)
-				} else {
-					current := workers.Load()
-					p := parallelism.Load()
-					for i := current; i < p; i++ {
-						wg.Add(1)
-						go startWorker()
-					}
-				}
-			case input <- text:
+				handleCommand(ctx, command)
+			case shardForSeq(seq) <- item:
+				break sendLoop
 			}
 		}
 	}
+	if ctx.Err() == nil {
+		finished = true
+		itemsWG.Wait() // let in-flight retries finish before tearing workers down
+	}
 exit:
-	close(input)
+	cancel()
 	wg.Wait()
+	if finished {
+		clearCheckpoint() // ran to completion normally; nothing left to resume
+	} else {
+		flushCheckpoint()
+	}
+	flushResults()
+	activeMonitor.final()
 }
 
-func startWorker() {
-	defer wg.Done()
-	idx := workers.Add(1) - 1
-	defer func() {
-		workers.Add(-1)
-	}()
-	t := time.NewTicker(time.Second)
-	workRate := rate.Load()
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	var last time.Time
-	for {
-		select {
-		case <-t.C:
-			workRate = rate.Load()
-			if parallelism.Load() <= int64(idx) {
-				return
-			}
-		case url, ok := <-input:
-			if !ok {
-				return
-			}
-			// enforce max speed
-			if time.Since(last) < time.Second/time.Duration(workRate) {
-				time.Sleep(time.Second/time.Duration(workRate) - time.Since(last))
-			}
-			last = time.Now()
-
-			resp, err := doReq(ctx, url)
-			if err != nil {
-				fmt.Println(url, errors.Wrap(err, "http"))
-			} else {
-				fmt.Println(url, resp.StatusCode)
-			}
-		}
+// handleCommand applies a live stdin setting command and, if it raised
+// parallelism, spawns the newly eligible workers.
+func handleCommand(ctx context.Context, command string) {
+	if true &&
+		// usage, to control parallelism, write: p=100
+		!setting("p", "parallelism", command, atomicIntSetting{parallelism}) &&
+		// usage, to control the max-rate per worker, set to 16/s, write: r=16
+		!setting("r", "rate", command, atomicIntSetting{rate}) &&
+		// usage, to control the per try timeout, write: t=10s
+		!setting("t", "timeout", command, durationSetting{&perTryTimeout}) &&
+		// usage, to control the retries, write: c=3
+		!setting("c", "retries", command, intSetting{&retries}) &&
+		// usage, to control the monitor's reporting interval in seconds, write: s=10
+		!setting("s", "report-interval", command, atomicIntSetting{reportInterval}) &&
+		// usage, to toggle HTTP keep-alives, write: keepalive=off
+		!setting("keepalive", "keepalive", command, boolOnOffSetting{keepAlive}) &&
+		// usage, to toggle HTTP/2, write: http2=off
+		!setting("http2", "http2", command, boolOnOffSetting{http2Enabled}) &&
+		// usage, to change the outgoing proxy, write: proxy=http://localhost:8080
+		!setting("proxy", "proxy", command, proxySetting{}) &&
+		// usage, to add/replace a default header, write: h=Name:Value
+		!setting("h", "header", command, headerSetting{}) &&
+		// usage, to change the default method, write: m=POST
+		!setting("m", "method", command, methodSetting{}) {
+		log.Println("unknown command", command)
+		return
+	}
+	// spawnWorker is idempotent, so just make sure every index up to the new
+	// parallelism has a goroutine; any already running (active or parked)
+	// are a no-op.
+	p := parallelism.Load()
+	for i := int64(0); i < p; i++ {
+		spawnWorker(ctx, i)
+	}
+	if affectsTransport(command) {
+		rebuildClient()
 	}
 }
 
-func doReq(ctx context.Context, url string) (resp *http.Response, err error) {
-	for i := retries; i > 0; i-- {
-		rctx, rcancel := context.WithTimeout(ctx, perTryTimeout)
-		defer rcancel()
-		req, _ := http.NewRequestWithContext(rctx, "GET", url, nil)
-		resp, err = http.DefaultClient.Do(req)
-		if err == nil {
-			return resp, nil
+// affectsTransport reports whether command changes a setting rebuildClient
+// reads (pool sizing or the transport's keepalive/http2/proxy config).
+// Settings like r=, t=, c=, s=, h= and m= are read directly at request time
+// and don't need the pooled transport torn down and rebuilt.
+func affectsTransport(command string) bool {
+	for _, prefix := range []string{"p=", "keepalive=", "http2=", "proxy="} {
+		if strings.HasPrefix(command, prefix) {
+			return true
 		}
 	}
-	return
+	return false
 }
 
 func setting(short, name, value string, storage storage) bool {
@@ -197,9 +222,16 @@ type durationSetting struct {
 	*time.Duration
 }
 
-func (d durationSetting) Store(val string) (err error) {
-	*d.Duration, err = time.ParseDuration(val)
-	return
+func (d durationSetting) Store(val string) error {
+	parsed, err := time.ParseDuration(val)
+	if err != nil {
+		return err
+	}
+	if parsed <= 0 {
+		return fmt.Errorf("timeout must be > 0, got %s", val)
+	}
+	*d.Duration = parsed
+	return nil
 }
 
 type intSetting struct {
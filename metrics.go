@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	stderrors "errors"
+	"net"
+	"net/http/httptrace"
+	"time"
+)
+
+// timing captures the httptrace checkpoints for a single request attempt,
+// so attemptReq can report DNS/connect/TLS/TTFB breakdowns alongside the status.
+type timing struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+}
+
+func (t *timing) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { t.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { t.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+}
+
+// since returns b.Sub(a), or 0 if either endpoint was never recorded.
+func since(b, a time.Time) time.Duration {
+	if a.IsZero() || b.IsZero() {
+		return 0
+	}
+	return b.Sub(a)
+}
+
+// errorClass buckets an attemptReq error into a coarse class for reporting,
+// so downstream consumers can aggregate without parsing error strings.
+func errorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case stderrors.As(err, &dnsErr):
+		return "dns"
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case stderrors.As(err, &opErr):
+		return "connect"
+	default:
+		return "other"
+	}
+}
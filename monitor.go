@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reportInterval is how often the monitor prints an aggregate summary, in
+// seconds; tune it live with the `s=` command, e.g. `s=10`.
+var reportInterval = &atomic.Int64{}
+
+// monitorCh carries completed-request samples from the workers to the monitor.
+// Sends are non-blocking: a full channel just drops the sample rather than
+// slowing down request dispatch.
+var monitorCh = make(chan monitorSample, 4096)
+
+type monitorSample struct {
+	status   int
+	errClass string
+	latency  time.Duration
+}
+
+func pushMonitorSample(r result) {
+	select {
+	case monitorCh <- monitorSample{status: r.Status, errClass: r.ErrorClass, latency: r.latency}:
+	default:
+	}
+}
+
+const reservoirSize = 1024
+
+// monitor aggregates request outcomes into rolling rps/latency stats and
+// prints a one-line summary to stderr every reportInterval seconds.
+type monitor struct {
+	startedAt time.Time
+	total     atomic.Int64
+	byClass   [6]atomic.Int64 // 0=error, 1..5=HTTP status class 1xx..5xx
+
+	mu          sync.Mutex
+	reservoir   []time.Duration
+	seen        int64
+	windowStart time.Time
+	windowCount int64
+}
+
+var activeMonitor = newMonitor()
+
+func newMonitor() *monitor {
+	now := time.Now()
+	return &monitor{startedAt: now, windowStart: now}
+}
+
+func statusClass(status int, errClass string) int {
+	if errClass != "" || status < 100 || status > 599 {
+		return 0
+	}
+	return status / 100
+}
+
+func (m *monitor) record(s monitorSample) {
+	m.total.Add(1)
+	m.byClass[statusClass(s.status, s.errClass)].Add(1)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.windowCount++
+	// reservoir sampling: replace a random existing slot once full, so the
+	// reservoir stays an unbiased sample of the current window.
+	if int64(len(m.reservoir)) < reservoirSize {
+		m.reservoir = append(m.reservoir, s.latency)
+	} else if i := rand.Int63n(m.seen + 1); i < reservoirSize {
+		m.reservoir[i] = s.latency
+	}
+	m.seen++
+}
+
+// percentiles returns p50/p90/p99 over the current window's reservoir.
+func (m *monitor) percentiles() (p50, p90, p99 time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.reservoir) == 0 {
+		return 0, 0, 0
+	}
+	sorted := append([]time.Duration(nil), m.reservoir...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	pick := func(p float64) time.Duration {
+		idx := int(p * float64(len(sorted)-1))
+		return sorted[idx]
+	}
+	return pick(0.50), pick(0.90), pick(0.99)
+}
+
+// resetWindow clears the rolling rps/latency window and returns its stats.
+func (m *monitor) resetWindow() (elapsed time.Duration, count int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elapsed = time.Since(m.windowStart)
+	count = m.windowCount
+	m.windowStart = time.Now()
+	m.windowCount = 0
+	m.reservoir = m.reservoir[:0]
+	m.seen = 0
+	return
+}
+
+func (m *monitor) report() {
+	p50, p90, p99 := m.percentiles()
+	elapsed, count := m.resetWindow()
+	var rps float64
+	if elapsed > 0 {
+		rps = float64(count) / elapsed.Seconds()
+	}
+	fmt.Fprintf(os.Stderr, "[monitor] total=%d inflight=%d rps=%.1f p50=%s p90=%s p99=%s\n",
+		m.total.Load(), workers.Load(), rps, p50, p90, p99)
+}
+
+// final prints the run summary; called once on shutdown (SIGINT or EOF).
+func (m *monitor) final() {
+	elapsed := time.Since(m.startedAt)
+	total := m.total.Load()
+	var rps float64
+	if elapsed > 0 {
+		rps = float64(total) / elapsed.Seconds()
+	}
+	fmt.Fprintf(os.Stderr, "[monitor] final: total=%d elapsed=%s avg_rps=%.1f 1xx=%d 2xx=%d 3xx=%d 4xx=%d 5xx=%d errors=%d\n",
+		total, elapsed.Round(time.Millisecond), rps,
+		m.byClass[1].Load(), m.byClass[2].Load(), m.byClass[3].Load(), m.byClass[4].Load(), m.byClass[5].Load(), m.byClass[0].Load())
+}
+
+// run consumes samples and ticks the periodic report until ctx is done.
+func (m *monitor) run(ctx context.Context) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	var sinceReport time.Duration
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-monitorCh:
+			m.record(s)
+		case <-t.C:
+			sinceReport += time.Second
+			interval := time.Duration(reportInterval.Load()) * time.Second
+			if interval <= 0 {
+				interval = 5 * time.Second
+			}
+			if sinceReport >= interval {
+				m.report()
+				sinceReport = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// outputFormat selects how results are rendered: text (default), json, ndjson or csv.
+var outputFormat = "text"
+
+// result is one completed (or retries-exhausted) request, ready for reporting.
+type result struct {
+	Seq        int64   `json:"seq"`
+	URL        string  `json:"url"`
+	Method     string  `json:"method"`
+	Status     int     `json:"status"`
+	Attempts   int     `json:"attempts"`
+	LatencyMS  float64 `json:"latency_ms"`
+	DNSMS      float64 `json:"dns_ms"`
+	ConnectMS  float64 `json:"connect_ms"`
+	TLSMS      float64 `json:"tls_ms"`
+	TTFBMS     float64 `json:"ttfb_ms"`
+	Bytes      int64   `json:"bytes"`
+	Error      string  `json:"error,omitempty"`
+	ErrorClass string  `json:"error_class,omitempty"`
+
+	latency time.Duration // raw latency, kept for the monitor; LatencyMS is the serialized form
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+var (
+	jsonResults   []result
+	jsonResultsMu sync.Mutex
+
+	csvWriter     *csv.Writer
+	csvHeaderOnce sync.Once
+	csvWriterMu   sync.Mutex
+)
+
+var csvHeader = []string{"seq", "url", "method", "status", "attempts", "latency_ms", "dns_ms", "connect_ms", "tls_ms", "ttfb_ms", "bytes", "error", "error_class"}
+
+// writeResult renders a single result according to outputFormat. For "json"
+// it buffers the record; call flushResults at the end of the run to emit the array.
+func writeResult(r result) {
+	switch outputFormat {
+	case "json":
+		jsonResultsMu.Lock()
+		jsonResults = append(jsonResults, r)
+		jsonResultsMu.Unlock()
+	case "ndjson":
+		b, err := json.Marshal(r)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "marshal result:", err)
+			return
+		}
+		fmt.Println(string(b))
+	case "csv":
+		writeCSVRow(r)
+	default:
+		if r.Error != "" {
+			fmt.Println(r.URL, r.Error)
+		} else {
+			fmt.Println(r.URL, r.Status)
+		}
+	}
+}
+
+func writeCSVRow(r result) {
+	csvHeaderOnce.Do(func() {
+		csvWriter = csv.NewWriter(os.Stdout)
+		if err := csvWriter.Write(csvHeader); err != nil {
+			fmt.Fprintln(os.Stderr, "csv header:", err)
+		}
+	})
+	row := []string{
+		strconv.FormatInt(r.Seq, 10),
+		r.URL,
+		r.Method,
+		strconv.Itoa(r.Status),
+		strconv.Itoa(r.Attempts),
+		strconv.FormatFloat(r.LatencyMS, 'f', 3, 64),
+		strconv.FormatFloat(r.DNSMS, 'f', 3, 64),
+		strconv.FormatFloat(r.ConnectMS, 'f', 3, 64),
+		strconv.FormatFloat(r.TLSMS, 'f', 3, 64),
+		strconv.FormatFloat(r.TTFBMS, 'f', 3, 64),
+		strconv.FormatInt(r.Bytes, 10),
+		r.Error,
+		r.ErrorClass,
+	}
+	csvWriterMu.Lock()
+	defer csvWriterMu.Unlock()
+	if err := csvWriter.Write(row); err != nil {
+		fmt.Fprintln(os.Stderr, "csv row:", err)
+		return
+	}
+	csvWriter.Flush()
+}
+
+// flushResults emits any buffered output once the run has finished; only the
+// "json" format buffers (it needs a closing array bracket), the rest stream live.
+func flushResults() {
+	if outputFormat != "json" {
+		return
+	}
+	jsonResultsMu.Lock()
+	defer jsonResultsMu.Unlock()
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(jsonResults); err != nil {
+		fmt.Fprintln(os.Stderr, "marshal results:", err)
+	}
+}
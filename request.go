@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Request is a fully-resolved unit of work: method, URL, headers, body and
+// (optionally) the expected status code, ready to be sent by attemptReq.
+// ExpectedStatus is 0 when the line didn't specify one, meaning any status
+// counts as success.
+type Request struct {
+	Method         string
+	URL            string
+	Header         http.Header
+	Body           []byte
+	ExpectedStatus int
+}
+
+// lineRe splits an input line into an optional leading METHOD, the URL, and
+// an optional trailing body, e.g. `POST https://api/x {"k":"v"}`. A bare
+// URL with nothing else still matches, with method and body left empty.
+var lineRe = regexp.MustCompile(`^(?:([A-Z]{3,7})\s+)?(\S+)(?:\s+(.*))?$`)
+
+// varRe matches ${name} placeholders for template substitution.
+var varRe = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// statusRe strips a trailing "=> NNN" expected-status marker off a line
+// before lineRe sees it, e.g. `GET https://api/x => 200` or
+// `POST https://api/x {"k":"v"} => 201`.
+var statusRe = regexp.MustCompile(`\s*=>\s*(\d{3})\s*$`)
+
+// substitute replaces ${name} placeholders in s with vars[name], leaving
+// unknown placeholders untouched. A nil/empty vars is a no-op.
+func substitute(s string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return s
+	}
+	return varRe.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := vars[m[2:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// parseLine turns one input line (or rendered template line) into a
+// Request, applying the default method/headers/body where the line doesn't
+// override them.
+func parseLine(text string, vars map[string]string) *Request {
+	text = substitute(text, vars)
+	req := &Request{
+		Method: defaultMethod,
+		Header: cloneDefaultHeader(),
+		Body:   defaultBody,
+	}
+	if sm := statusRe.FindStringSubmatch(text); sm != nil {
+		req.ExpectedStatus, _ = strconv.Atoi(sm[1])
+		text = text[:len(text)-len(sm[0])]
+	}
+	m := lineRe.FindStringSubmatch(text)
+	if m == nil {
+		req.URL = text
+		return req
+	}
+	if m[1] != "" {
+		req.Method = m[1]
+	}
+	req.URL = m[2]
+	if m[3] != "" {
+		req.Body = []byte(m[3])
+	}
+	return req
+}
+
+// splitRow splits a template-mode input row on tabs if present, else commas.
+func splitRow(line string) []string {
+	if strings.Contains(line, "\t") {
+		return strings.Split(line, "\t")
+	}
+	return strings.Split(line, ",")
+}
+
+// rowVars zips a TSV/CSV header row with a data row into a ${name} -> value map.
+func rowVars(columns, row []string) map[string]string {
+	vars := make(map[string]string, len(columns))
+	for i, name := range columns {
+		if i < len(row) {
+			vars[strings.TrimSpace(name)] = strings.TrimSpace(row[i])
+		}
+	}
+	return vars
+}
+
+var (
+	headerMu      sync.Mutex
+	defaultHeader = http.Header{}
+)
+
+// initDefaultHeaders seeds defaultHeader from the -header/headers flags.
+func initDefaultHeaders() {
+	headerMu.Lock()
+	defer headerMu.Unlock()
+	for _, line := range defaultHeaderLines {
+		if k, v, ok := splitHeaderLine(line); ok {
+			defaultHeader.Set(k, v)
+		}
+	}
+}
+
+func splitHeaderLine(line string) (name, value string, ok bool) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
+
+// setDefaultHeader appends/replaces a default header; see the `h=` live command.
+func setDefaultHeader(name, value string) {
+	headerMu.Lock()
+	defer headerMu.Unlock()
+	defaultHeader.Set(name, value)
+}
+
+func cloneDefaultHeader() http.Header {
+	headerMu.Lock()
+	defer headerMu.Unlock()
+	h := make(http.Header, len(defaultHeader))
+	for k, v := range defaultHeader {
+		vv := make([]string, len(v))
+		copy(vv, v)
+		h[k] = vv
+	}
+	return h
+}
+
+var (
+	defaultBody     []byte
+	defaultBodyOnce sync.Once
+)
+
+// loadDefaultBody reads -body-file once, at startup, into defaultBody.
+func loadDefaultBody() {
+	defaultBodyOnce.Do(func() {
+		if defaultBodyFile == "" {
+			return
+		}
+		b, err := os.ReadFile(defaultBodyFile)
+		if err != nil {
+			log.Println("body-file:", err)
+			return
+		}
+		defaultBody = b
+	})
+}
+
+// headerSetting adapts the default header set to the `storage` interface,
+// driven by "h=Name:Value" live commands.
+type headerSetting struct{}
+
+func (headerSetting) Store(val string) error {
+	name, value, ok := splitHeaderLine(val)
+	if !ok {
+		return fmt.Errorf("expected Name:Value, got %q", val)
+	}
+	setDefaultHeader(name, value)
+	return nil
+}
+
+// methodSetting adapts the default method to the `storage` interface, driven
+// by "m=POST" live commands.
+type methodSetting struct{}
+
+func (methodSetting) Store(val string) error {
+	defaultMethod = val
+	return nil
+}
+
+// copyResponseBody drains resp's body, saving it under -out-dir named by
+// SHA256(url) when set, or discarding it otherwise; returns bytes read.
+func copyResponseBody(url string, body io.Reader) int64 {
+	if outDir == "" {
+		n, _ := io.Copy(io.Discard, body)
+		return n
+	}
+	n, err := saveResponseBody(url, body)
+	if err != nil {
+		log.Println("out-dir:", err)
+	}
+	return n
+}
+
+func saveResponseBody(url string, body io.Reader) (int64, error) {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return 0, err
+	}
+	sum := sha256.Sum256([]byte(url))
+	f, err := os.Create(filepath.Join(outDir, hex.EncodeToString(sum[:])))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(f, body)
+}
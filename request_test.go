@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	cases := []struct {
+		name          string
+		line          string
+		vars          map[string]string
+		wantMethod    string
+		wantURL       string
+		wantBody      string
+		wantExpStatus int
+	}{
+		{
+			name:       "bare url",
+			line:       "https://api.example.com/x",
+			wantMethod: "GET",
+			wantURL:    "https://api.example.com/x",
+		},
+		{
+			name:       "method and url",
+			line:       "POST https://api.example.com/x",
+			wantMethod: "POST",
+			wantURL:    "https://api.example.com/x",
+		},
+		{
+			name:       "method, url and body",
+			line:       `POST https://api.example.com/x {"k":"v"}`,
+			wantMethod: "POST",
+			wantURL:    "https://api.example.com/x",
+			wantBody:   `{"k":"v"}`,
+		},
+		{
+			name:          "expected status on a bare url",
+			line:          "https://api.example.com/x => 204",
+			wantMethod:    "GET",
+			wantURL:       "https://api.example.com/x",
+			wantExpStatus: 204,
+		},
+		{
+			name:          "expected status after a body",
+			line:          `POST https://api.example.com/x {"k":"v"} => 201`,
+			wantMethod:    "POST",
+			wantURL:       "https://api.example.com/x",
+			wantBody:      `{"k":"v"}`,
+			wantExpStatus: 201,
+		},
+		{
+			name:       "var substitution in url and body",
+			line:       `POST ${base}/x {"id":"${id}"}`,
+			vars:       map[string]string{"base": "https://api.example.com", "id": "42"},
+			wantMethod: "POST",
+			wantURL:    "https://api.example.com/x",
+			wantBody:   `{"id":"42"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			defaultMethod = "GET"
+			req := parseLine(tc.line, tc.vars)
+			if req.Method != tc.wantMethod {
+				t.Errorf("Method = %q, want %q", req.Method, tc.wantMethod)
+			}
+			if req.URL != tc.wantURL {
+				t.Errorf("URL = %q, want %q", req.URL, tc.wantURL)
+			}
+			if string(req.Body) != tc.wantBody {
+				t.Errorf("Body = %q, want %q", req.Body, tc.wantBody)
+			}
+			if req.ExpectedStatus != tc.wantExpStatus {
+				t.Errorf("ExpectedStatus = %d, want %d", req.ExpectedStatus, tc.wantExpStatus)
+			}
+		})
+	}
+}
+
+func TestRowVars(t *testing.T) {
+	columns := []string{"id", "name"}
+	row := []string{"1", "alice"}
+	vars := rowVars(columns, row)
+	if vars["id"] != "1" || vars["name"] != "alice" {
+		t.Errorf("rowVars(%v, %v) = %v", columns, row, vars)
+	}
+
+	// a short row leaves trailing columns unset rather than panicking
+	short := rowVars(columns, []string{"1"})
+	if _, ok := short["name"]; ok {
+		t.Errorf("rowVars with a short row should not set trailing columns, got %v", short)
+	}
+}
+
+func TestSplitHeaderLine(t *testing.T) {
+	name, value, ok := splitHeaderLine("X-Request-Id: abc-123")
+	if !ok || name != "X-Request-Id" || value != "abc-123" {
+		t.Errorf("splitHeaderLine = (%q, %q, %v), want (%q, %q, true)", name, value, ok, "X-Request-Id", "abc-123")
+	}
+
+	if _, _, ok := splitHeaderLine("not-a-header"); ok {
+		t.Errorf("splitHeaderLine(%q) should fail", "not-a-header")
+	}
+}
+
+func TestHeaderSettingStore(t *testing.T) {
+	defer func() { defaultHeader = http.Header{} }()
+
+	var s headerSetting
+	if err := s.Store("X-Test: one"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if got := cloneDefaultHeader().Get("X-Test"); got != "one" {
+		t.Errorf("header X-Test = %q, want %q", got, "one")
+	}
+
+	if err := s.Store("missing-colon"); err == nil {
+		t.Errorf("Store(%q) should have failed", "missing-colon")
+	}
+}